@@ -1,28 +1,120 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"go.etcd.io/bbolt"
 
 	"calendar/internal/calendar"
 	"calendar/internal/httpserver"
 )
 
 func main() {
-	var port string
+	var port, storage, dsn, authSecret string
+	var drainTimeout time.Duration
+	var rateRPS float64
+	var rateBurst int
 	flag.StringVar(&port, "port", getenv("PORT", "8080"), "HTTP server port")
+	flag.StringVar(&storage, "storage", getenv("STORAGE", "memory"), "storage backend: memory, sqlite, postgres, bolt")
+	flag.StringVar(&dsn, "storage-dsn", os.Getenv("STORAGE_DSN"), "data source name for the sqlite/postgres/bolt backend")
+	flag.StringVar(&authSecret, "auth-secret", os.Getenv("AUTH_SECRET"), "shared secret for verifying signed bearer tokens")
+	flag.Float64Var(&rateRPS, "rate-limit-rps", 10, "sustained requests per second allowed per authenticated user")
+	flag.IntVar(&rateBurst, "rate-limit-burst", 20, "burst requests allowed per authenticated user")
+	flag.DurationVar(&drainTimeout, "drain-timeout", 15*time.Second, "how long to wait for in-flight requests to finish on shutdown")
 	flag.Parse()
 
-	svc := calendar.NewService()
+	if authSecret == "" {
+		log.Fatal("auth secret is required: set -auth-secret or AUTH_SECRET")
+	}
+
+	store, err := newStore(storage, dsn)
+	if err != nil {
+		log.Fatalf("failed to open %s storage: %v", storage, err)
+	}
+
+	svc := calendar.NewServiceWithStore(store)
 	srv := httpserver.New(svc)
-	h := httpserver.LoggingMiddleware(srv.Router())
+	chain := httpserver.Chain(
+		httpserver.RequestID,
+		httpserver.Recover,
+		httpserver.LoggingMiddleware,
+		httpserver.Auth([]byte(authSecret)),
+		httpserver.RateLimit(rateRPS, rateBurst),
+	)
+	h := chain(srv.Router())
+
+	httpSrv := &http.Server{
+		Addr:    ":" + port,
+		Handler: h,
+		BaseContext: func(net.Listener) context.Context {
+			return context.Background()
+		},
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       90 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		log.Printf("Starting server on %s (storage=%s)", httpSrv.Addr, storage)
+		if err := httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("server error: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Printf("Shutting down (draining up to %s)...", drainTimeout)
+	srv.SetShuttingDown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	if err := httpSrv.Shutdown(shutdownCtx); err != nil {
+		log.Fatalf("shutdown error: %v", err)
+	}
+}
 
-	addr := ":" + port
-	log.Printf("Starting server on %s", addr)
-	if err := http.ListenAndServe(addr, h); err != nil {
-		log.Fatalf("server error: %v", err)
+// newStore builds the Store named by storage, opening the underlying
+// database/file at dsn as needed.
+func newStore(storage, dsn string) (calendar.Store, error) {
+	switch storage {
+	case "", "memory":
+		return calendar.NewMemoryStore(), nil
+	case "sqlite":
+		db, err := sql.Open("sqlite3", dsn)
+		if err != nil {
+			return nil, err
+		}
+		return calendar.NewSQLStore(db, "sqlite")
+	case "postgres":
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			return nil, err
+		}
+		return calendar.NewSQLStore(db, "postgres")
+	case "bolt":
+		db, err := bbolt.Open(dsn, 0600, nil)
+		if err != nil {
+			return nil, err
+		}
+		return calendar.NewBoltStore(db), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", storage)
 	}
 }
 
@@ -1,19 +1,61 @@
 package calendar
 
 import (
+	"context"
 	"errors"
 	"sort"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Event struct {
-	ID     string    `json:"id"`
-	UserID int64     `json:"user_id"`
-	Date   time.Time `json:"date"`
-	Text   string    `json:"event"`
+	ID     string `json:"id"`
+	UserID int64  `json:"user_id"`
+	// Date is the calendar day StartAt falls on, expressed in Timezone (or
+	// UTC if Timezone is unset). It's recomputed from StartAt on every
+	// create/update, so callers should treat it as read-only.
+	Date time.Time `json:"date"`
+	// StartAt and EndAt are the event's actual instants. EndAt is the zero
+	// time when the event has no explicit end.
+	StartAt time.Time `json:"start_at"`
+	EndAt   time.Time `json:"end_at,omitempty"`
+	// Timezone is the IANA name (e.g. "Europe/Moscow") StartAt/EndAt and the
+	// recurrence rule are anchored in. Empty means UTC.
+	Timezone string `json:"timezone,omitempty"`
+	Text     string `json:"event"`
+
+	// Recurrence is an RFC 5545 RRULE string (e.g. "FREQ=WEEKLY;BYDAY=MO,WE").
+	// Empty for a non-recurring event. Set only on the master event; expanded
+	// occurrences returned by EventsForDay/Week/Month carry it too so callers
+	// can tell which series an instance belongs to.
+	Recurrence string `json:"recurrence,omitempty"`
+	// ExDates lists occurrence dates excluded from the master's expansion,
+	// e.g. because a single instance was deleted.
+	ExDates []time.Time `json:"exdates,omitempty"`
+}
+
+// IsOccurrence reports whether id identifies a single expanded occurrence
+// (<master-id>@<yyyy-mm-dd>) rather than a master event.
+func IsOccurrence(id string) bool {
+	return strings.Contains(id, "@")
+}
+
+func occurrenceID(masterID string, date time.Time) string {
+	return masterID + "@" + date.Format("2006-01-02")
+}
+
+func splitOccurrenceID(id string) (masterID string, date time.Time, ok bool) {
+	i := strings.LastIndex(id, "@")
+	if i < 0 {
+		return "", time.Time{}, false
+	}
+	d, err := time.Parse("2006-01-02", id[i+1:])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return id[:i], d, true
 }
 
 var (
@@ -24,44 +66,85 @@ var (
 )
 
 type Service struct {
-	mu              sync.RWMutex
-	userToEventsMap map[int64]map[string]Event
+	store Store
 }
 
+// NewService returns a Service backed by a fresh, non-persistent MemoryStore.
 func NewService() *Service {
-	return &Service{userToEventsMap: make(map[int64]map[string]Event)}
+	return NewServiceWithStore(NewMemoryStore())
+}
+
+// NewServiceWithStore returns a Service backed by the given Store, letting
+// callers plug in a persistent backend (SQL, BoltDB, ...).
+func NewServiceWithStore(store Store) *Service {
+	return &Service{store: store}
 }
 
 func normalizeDate(d time.Time) time.Time {
 	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, d.Location())
 }
 
-func (s *Service) CreateEvent(userID int64, date time.Time, text string) (Event, error) {
+// EventLocation resolves tz to an IANA location, defaulting to UTC for an
+// empty or unrecognized name rather than failing the request. Exported so
+// httpserver can interpret tz-naive timestamp input in the same location
+// before it ever reaches the Service.
+func EventLocation(tz string) *time.Location {
+	if tz == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// dateInLocation reinterprets d's year/month/day as midnight in loc,
+// ignoring d's own location. It's how a bare "2024-01-01" query date is
+// turned into the start of that calendar day in the requested timezone.
+func dateInLocation(d time.Time, loc *time.Location) time.Time {
+	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, loc)
+}
+
+func (s *Service) CreateEvent(ctx context.Context, userID int64, startAt, endAt time.Time, tz, text, recurrence string) (Event, error) {
 	if userID <= 0 {
 		return Event{}, ErrInvalidUserID
 	}
 	if text == "" {
 		return Event{}, ErrInvalidText
 	}
-	date = normalizeDate(date)
+	if recurrence != "" {
+		if _, err := ParseRRule(recurrence); err != nil {
+			return Event{}, err
+		}
+	}
+	loc := EventLocation(tz)
+	startAt = startAt.In(loc)
+	if !endAt.IsZero() {
+		endAt = endAt.In(loc)
+	}
 
 	newEvent := Event{
-		ID:     uuid.NewString(),
-		UserID: userID,
-		Date:   date,
-		Text:   text,
+		ID:         uuid.NewString(),
+		UserID:     userID,
+		Date:       normalizeDate(startAt),
+		StartAt:    startAt,
+		EndAt:      endAt,
+		Timezone:   tz,
+		Text:       text,
+		Recurrence: recurrence,
 	}
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	if _, ok := s.userToEventsMap[userID]; !ok {
-		s.userToEventsMap[userID] = make(map[string]Event)
+	if err := s.store.Create(ctx, newEvent); err != nil {
+		return Event{}, err
 	}
-	s.userToEventsMap[userID][newEvent.ID] = newEvent
 	return newEvent, nil
 }
 
-func (s *Service) UpdateEvent(id string, userID int64, date time.Time, text string) (Event, error) {
+// UpdateEvent updates the event identified by id. If id names a single
+// occurrence of a recurring series (<master-id>@<yyyy-mm-dd>), that date is
+// added to the master's EXDATE list and a new standalone, non-recurring event
+// is created in its place; the master series itself is left otherwise intact.
+func (s *Service) UpdateEvent(ctx context.Context, id string, userID int64, startAt, endAt time.Time, tz, text, recurrence string) (Event, error) {
 	if id == "" {
 		return Event{}, ErrNotFound
 	}
@@ -71,72 +154,118 @@ func (s *Service) UpdateEvent(id string, userID int64, date time.Time, text stri
 	if text == "" {
 		return Event{}, ErrInvalidText
 	}
-	date = normalizeDate(date)
+	if recurrence != "" {
+		if _, err := ParseRRule(recurrence); err != nil {
+			return Event{}, err
+		}
+	}
+	loc := EventLocation(tz)
+	startAt = startAt.In(loc)
+	if !endAt.IsZero() {
+		endAt = endAt.In(loc)
+	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	if masterID, occDate, isOcc := splitOccurrenceID(id); isOcc {
+		master, err := s.store.Get(ctx, userID, masterID)
+		if err != nil || master.Recurrence == "" {
+			return Event{}, ErrNotFound
+		}
+		master.ExDates = append(master.ExDates, occDate)
+		if err := s.store.Update(ctx, master); err != nil {
+			return Event{}, err
+		}
 
-	userEvents, ok := s.userToEventsMap[userID]
-	if !ok {
-		return Event{}, ErrNotFound
+		detached := Event{
+			ID:       uuid.NewString(),
+			UserID:   userID,
+			Date:     normalizeDate(startAt),
+			StartAt:  startAt,
+			EndAt:    endAt,
+			Timezone: tz,
+			Text:     text,
+		}
+		if err := s.store.Create(ctx, detached); err != nil {
+			return Event{}, err
+		}
+		return detached, nil
 	}
-	ev, ok := userEvents[id]
-	if !ok {
+
+	ev, err := s.store.Get(ctx, userID, id)
+	if err != nil {
 		return Event{}, ErrNotFound
 	}
-	ev.Date = date
+	ev.Date = normalizeDate(startAt)
+	ev.StartAt = startAt
+	ev.EndAt = endAt
+	ev.Timezone = tz
 	ev.Text = text
-	userEvents[id] = ev
+	ev.Recurrence = recurrence
+	if err := s.store.Update(ctx, ev); err != nil {
+		return Event{}, err
+	}
 	return ev, nil
 }
 
-func (s *Service) DeleteEvent(id string, userID int64) error {
+// DeleteEvent removes the event identified by id. If id names a single
+// occurrence of a recurring series, only that date is excluded (added to the
+// master's EXDATE list); the rest of the series is unaffected.
+func (s *Service) DeleteEvent(ctx context.Context, id string, userID int64) error {
 	if id == "" {
 		return ErrNotFound
 	}
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if userID > 0 {
-		userEvents, ok := s.userToEventsMap[userID]
-		if !ok {
-			return ErrNotFound
-		}
-		if _, ok := userEvents[id]; !ok {
+	if masterID, occDate, isOcc := splitOccurrenceID(id); isOcc {
+		master, err := s.store.Get(ctx, userID, masterID)
+		if err != nil || master.Recurrence == "" {
 			return ErrNotFound
 		}
-		delete(userEvents, id)
-		return nil
+		master.ExDates = append(master.ExDates, occDate)
+		return s.store.Update(ctx, master)
 	}
+	return s.store.Delete(ctx, userID, id)
+}
 
-	for uid, userEvents := range s.userToEventsMap {
-		if _, ok := userEvents[id]; ok {
-			delete(userEvents, id)
-			if len(userEvents) == 0 {
-				delete(s.userToEventsMap, uid)
-			}
-			return nil
+// expand returns ev itself if it isn't recurring and its StartAt falls within
+// [start, end), or every occurrence of its series that falls within
+// [start, end) if it is recurring.
+func expand(ev Event, start, end time.Time) []Event {
+	if ev.Recurrence == "" {
+		if (ev.StartAt.Equal(start) || ev.StartAt.After(start)) && ev.StartAt.Before(end) {
+			return []Event{ev}
 		}
+		return nil
+	}
+	rule, err := ParseRRule(ev.Recurrence)
+	if err != nil {
+		return nil
 	}
-	return ErrNotFound
+	occurrences := rule.Expand(ev.StartAt, start, end, ev.ExDates)
+	res := make([]Event, 0, len(occurrences))
+	for _, d := range occurrences {
+		res = append(res, Event{
+			ID:         occurrenceID(ev.ID, d),
+			UserID:     ev.UserID,
+			Date:       normalizeDate(d),
+			StartAt:    d,
+			EndAt:      ev.EndAt,
+			Timezone:   ev.Timezone,
+			Text:       ev.Text,
+			Recurrence: ev.Recurrence,
+		})
+	}
+	return res
 }
 
-func (s *Service) EventsForDay(userID int64, day time.Time) ([]Event, error) {
+func (s *Service) eventsInRange(ctx context.Context, userID int64, start, end time.Time) ([]Event, error) {
 	if userID <= 0 {
 		return nil, ErrInvalidUserID
 	}
-	day = normalizeDate(day)
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	userEvents, ok := s.userToEventsMap[userID]
-	if !ok {
-		return []Event{}, nil
+	userEvents, err := s.store.ListInRange(ctx, userID, start, end)
+	if err != nil {
+		return nil, err
 	}
-	res := make([]Event, 0)
+	res := make([]Event, 0, len(userEvents))
 	for _, ev := range userEvents {
-		if ev.Date.Equal(day) {
-			res = append(res, ev)
-		}
+		res = append(res, expand(ev, start, end)...)
 	}
 	sort.Slice(res, func(i, j int) bool {
 		return res[i].Date.Before(res[j].Date) || (res[i].Date.Equal(res[j].Date) && res[i].ID < res[j].ID)
@@ -144,58 +273,30 @@ func (s *Service) EventsForDay(userID int64, day time.Time) ([]Event, error) {
 	return res, nil
 }
 
-func (s *Service) EventsForWeek(userID int64, anyDay time.Time) ([]Event, error) {
-	if userID <= 0 {
-		return nil, ErrInvalidUserID
-	}
-	anyDay = normalizeDate(anyDay)
+// EventsForDay returns the events occurring on day, where day is bucketed as
+// a calendar date in tz (an IANA name; empty means UTC).
+func (s *Service) EventsForDay(ctx context.Context, userID int64, day time.Time, tz string) ([]Event, error) {
+	start := dateInLocation(day, EventLocation(tz))
+	return s.eventsInRange(ctx, userID, start, start.AddDate(0, 0, 1))
+}
+
+// EventsForWeek returns the events in the ISO (Monday-start) week containing
+// anyDay, bucketed in tz.
+func (s *Service) EventsForWeek(ctx context.Context, userID int64, anyDay time.Time, tz string) ([]Event, error) {
+	anyDay = dateInLocation(anyDay, EventLocation(tz))
 	weekday := int(anyDay.Weekday())
 	if weekday == 0 {
 		weekday = 7
 	}
 	start := anyDay.AddDate(0, 0, -(weekday - 1))
-	end := start.AddDate(0, 0, 7)
-
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	userEvents, ok := s.userToEventsMap[userID]
-	if !ok {
-		return []Event{}, nil
-	}
-	res := make([]Event, 0)
-	for _, ev := range userEvents {
-		if (ev.Date.Equal(start) || ev.Date.After(start)) && ev.Date.Before(end) {
-			res = append(res, ev)
-		}
-	}
-	sort.Slice(res, func(i, j int) bool {
-		return res[i].Date.Before(res[j].Date) || (res[i].Date.Equal(res[j].Date) && res[i].ID < res[j].ID)
-	})
-	return res, nil
+	return s.eventsInRange(ctx, userID, start, start.AddDate(0, 0, 7))
 }
 
-func (s *Service) EventsForMonth(userID int64, anyDay time.Time) ([]Event, error) {
-	if userID <= 0 {
-		return nil, ErrInvalidUserID
-	}
-	anyDay = normalizeDate(anyDay)
-	start := time.Date(anyDay.Year(), anyDay.Month(), 1, 0, 0, 0, 0, anyDay.Location())
-	end := start.AddDate(0, 1, 0)
-
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	userEvents, ok := s.userToEventsMap[userID]
-	if !ok {
-		return []Event{}, nil
-	}
-	res := make([]Event, 0)
-	for _, ev := range userEvents {
-		if (ev.Date.Equal(start) || ev.Date.After(start)) && ev.Date.Before(end) {
-			res = append(res, ev)
-		}
-	}
-	sort.Slice(res, func(i, j int) bool {
-		return res[i].Date.Before(res[j].Date) || (res[i].Date.Equal(res[j].Date) && res[i].ID < res[j].ID)
-	})
-	return res, nil
+// EventsForMonth returns the events in the calendar month containing anyDay,
+// bucketed in tz.
+func (s *Service) EventsForMonth(ctx context.Context, userID int64, anyDay time.Time, tz string) ([]Event, error) {
+	loc := EventLocation(tz)
+	anyDay = dateInLocation(anyDay, loc)
+	start := time.Date(anyDay.Year(), anyDay.Month(), 1, 0, 0, 0, 0, loc)
+	return s.eventsInRange(ctx, userID, start, start.AddDate(0, 1, 0))
 }
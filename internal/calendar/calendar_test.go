@@ -1,10 +1,13 @@
 package calendar
 
 import (
+	"context"
 	"testing"
 	"time"
 )
 
+var ctx = context.Background()
+
 func mustDate(t *testing.T, s string) time.Time {
 	t.Helper()
 	d, err := time.Parse("2006-01-02", s)
@@ -17,10 +20,10 @@ func mustDate(t *testing.T, s string) time.Time {
 func TestCreateAndGetByDay(t *testing.T) {
 	s := NewService()
 	day := mustDate(t, "2023-12-31")
-	if _, err := s.CreateEvent(1, day, "New Year Eve"); err != nil {
+	if _, err := s.CreateEvent(ctx, 1, day, time.Time{}, "", "New Year Eve", ""); err != nil {
 		t.Fatalf("CreateEvent: %v", err)
 	}
-	events, err := s.EventsForDay(1, day)
+	events, err := s.EventsForDay(ctx, 1, day, "")
 	if err != nil {
 		t.Fatalf("EventsForDay: %v", err)
 	}
@@ -31,25 +34,24 @@ func TestCreateAndGetByDay(t *testing.T) {
 
 func TestWeekAndMonth(t *testing.T) {
 	s := NewService()
-	_ = mustDate(t, "2023-12-25")
-	if _, err := s.CreateEvent(1, mustDate(t, "2023-12-25"), "Mon"); err != nil {
+	if _, err := s.CreateEvent(ctx, 1, mustDate(t, "2023-12-25"), time.Time{}, "", "Mon", ""); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := s.CreateEvent(1, mustDate(t, "2023-12-31"), "Sun"); err != nil {
+	if _, err := s.CreateEvent(ctx, 1, mustDate(t, "2023-12-31"), time.Time{}, "", "Sun", ""); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := s.CreateEvent(1, mustDate(t, "2024-01-01"), "NextMonth"); err != nil {
+	if _, err := s.CreateEvent(ctx, 1, mustDate(t, "2024-01-01"), time.Time{}, "", "NextMonth", ""); err != nil {
 		t.Fatal(err)
 	}
 
-	weekEvents, err := s.EventsForWeek(1, mustDate(t, "2023-12-27"))
+	weekEvents, err := s.EventsForWeek(ctx, 1, mustDate(t, "2023-12-27"), "")
 	if err != nil {
 		t.Fatal(err)
 	}
 	if len(weekEvents) != 2 {
 		t.Fatalf("want 2 events in week, got %d", len(weekEvents))
 	}
-	monthEvents, err := s.EventsForMonth(1, mustDate(t, "2023-12-10"))
+	monthEvents, err := s.EventsForMonth(ctx, 1, mustDate(t, "2023-12-10"), "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -60,21 +62,21 @@ func TestWeekAndMonth(t *testing.T) {
 
 func TestUpdateAndDelete(t *testing.T) {
 	s := NewService()
-	ev, err := s.CreateEvent(2, mustDate(t, "2023-03-05"), "Text")
+	ev, err := s.CreateEvent(ctx, 2, mustDate(t, "2023-03-05"), time.Time{}, "", "Text", "")
 	if err != nil {
 		t.Fatal(err)
 	}
-	ev2, err := s.UpdateEvent(ev.ID, 2, mustDate(t, "2023-03-06"), "Text2")
+	ev2, err := s.UpdateEvent(ctx, ev.ID, 2, mustDate(t, "2023-03-06"), time.Time{}, "", "Text2", "")
 	if err != nil {
 		t.Fatal(err)
 	}
 	if ev2.Text != "Text2" || !ev2.Date.Equal(mustDate(t, "2023-03-06")) {
 		t.Fatalf("unexpected updated: %+v", ev2)
 	}
-	if err := s.DeleteEvent(ev.ID, 2); err != nil {
+	if err := s.DeleteEvent(ctx, ev.ID, 2); err != nil {
 		t.Fatal(err)
 	}
-	if err := s.DeleteEvent(ev.ID, 2); !errorsIs(err, ErrNotFound) {
+	if err := s.DeleteEvent(ctx, ev.ID, 2); !errorsIs(err, ErrNotFound) {
 		t.Fatalf("expected ErrNotFound, got %v", err)
 	}
 }
@@ -85,3 +87,166 @@ func errorsIs(err, target error) bool {
 	}
 	return err.Error() == target.Error()
 }
+
+func TestWeeklyRecurrenceExpandsByDay(t *testing.T) {
+	s := NewService()
+	// Monday 2024-01-01; recur every Mon/Wed until end of January.
+	if _, err := s.CreateEvent(ctx, 1, mustDate(t, "2024-01-01"), time.Time{}, "", "Standup", "FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20240131T000000Z"); err != nil {
+		t.Fatal(err)
+	}
+	events, err := s.EventsForMonth(ctx, 1, mustDate(t, "2024-01-15"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Jan 2024 Mondays: 1,8,15,22,29; Wednesdays: 3,10,17,24,31 (31 falls exactly on UNTIL, still included).
+	if len(events) != 10 {
+		t.Fatalf("want 10 occurrences, got %d: %+v", len(events), events)
+	}
+	for _, ev := range events {
+		if !IsOccurrence(ev.ID) {
+			t.Fatalf("expected synthetic occurrence ID, got %q", ev.ID)
+		}
+	}
+}
+
+func TestMonthlyRecurrenceByMonthDay(t *testing.T) {
+	s := NewService()
+	if _, err := s.CreateEvent(ctx, 1, mustDate(t, "2024-01-15"), time.Time{}, "", "Rent", "FREQ=MONTHLY;BYMONTHDAY=15;COUNT=3"); err != nil {
+		t.Fatal(err)
+	}
+	jan, err := s.EventsForMonth(ctx, 1, mustDate(t, "2024-01-01"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jan) != 1 {
+		t.Fatalf("want 1 Jan occurrence, got %d", len(jan))
+	}
+	march, err := s.EventsForMonth(ctx, 1, mustDate(t, "2024-03-01"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(march) != 1 {
+		t.Fatalf("want 1 Mar occurrence, got %d", len(march))
+	}
+	april, err := s.EventsForMonth(ctx, 1, mustDate(t, "2024-04-01"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(april) != 0 {
+		t.Fatalf("COUNT=3 should stop before April, got %+v", april)
+	}
+}
+
+func TestRecurrenceMonthBoundaryQuery(t *testing.T) {
+	s := NewService()
+	if _, err := s.CreateEvent(ctx, 1, mustDate(t, "2024-01-31"), time.Time{}, "", "Monthly", "FREQ=MONTHLY;BYMONTHDAY=31"); err != nil {
+		t.Fatal(err)
+	}
+	feb, err := s.EventsForMonth(ctx, 1, mustDate(t, "2024-02-10"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// February has no 31st, so the occurrence is skipped rather than clamped.
+	if len(feb) != 0 {
+		t.Fatalf("want 0 Feb occurrences, got %+v", feb)
+	}
+	march, err := s.EventsForMonth(ctx, 1, mustDate(t, "2024-03-10"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(march) != 1 {
+		t.Fatalf("want 1 Mar occurrence, got %+v", march)
+	}
+}
+
+func TestDeleteSingleOccurrenceKeepsSeries(t *testing.T) {
+	s := NewService()
+	master, err := s.CreateEvent(ctx, 1, mustDate(t, "2024-01-01"), time.Time{}, "", "Standup", "FREQ=DAILY;COUNT=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	occID := master.ID + "@2024-01-02"
+	if err := s.DeleteEvent(ctx, occID, 1); err != nil {
+		t.Fatalf("delete occurrence: %v", err)
+	}
+	events, err := s.EventsForWeek(ctx, 1, mustDate(t, "2024-01-01"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("want 4 remaining occurrences, got %d: %+v", len(events), events)
+	}
+	for _, ev := range events {
+		if ev.Date.Equal(mustDate(t, "2024-01-02")) {
+			t.Fatalf("excluded date still present: %+v", ev)
+		}
+	}
+}
+
+func TestEventsForDayAcrossTimezonesNoDoubleCount(t *testing.T) {
+	s := NewService()
+	// Sunday 2024-01-14 23:30 in Los Angeles, which is already Monday
+	// 2024-01-15 in Tokyo.
+	laStart, err := time.ParseInLocation("2006-01-02T15:04", "2024-01-14T23:30", mustLoadLocation(t, "America/Los_Angeles"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateEvent(ctx, 1, laStart, time.Time{}, "America/Los_Angeles", "Late call", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	sunInTokyo, err := s.EventsForDay(ctx, 1, mustDate(t, "2024-01-14"), "Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sunInTokyo) != 0 {
+		t.Fatalf("want 0 events on Sun in Tokyo, got %+v", sunInTokyo)
+	}
+
+	monInTokyo, err := s.EventsForDay(ctx, 1, mustDate(t, "2024-01-15"), "Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(monInTokyo) != 1 {
+		t.Fatalf("want 1 event on Mon in Tokyo, got %+v", monInTokyo)
+	}
+}
+
+func TestEventsForWeekUsesISOMondayStartInRequestedTZ(t *testing.T) {
+	s := NewService()
+	// Sunday 2024-01-14 23:30 America/Los_Angeles, which falls in the ISO
+	// week of Jan 15-21 when bucketed in Asia/Tokyo but the week of Jan 8-14
+	// when bucketed in its own zone.
+	laStart, err := time.ParseInLocation("2006-01-02T15:04", "2024-01-14T23:30", mustLoadLocation(t, "America/Los_Angeles"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateEvent(ctx, 1, laStart, time.Time{}, "America/Los_Angeles", "Late call", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	prevWeekTokyo, err := s.EventsForWeek(ctx, 1, mustDate(t, "2024-01-10"), "Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prevWeekTokyo) != 0 {
+		t.Fatalf("want 0 events in prior Tokyo week, got %+v", prevWeekTokyo)
+	}
+
+	weekTokyo, err := s.EventsForWeek(ctx, 1, mustDate(t, "2024-01-17"), "Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(weekTokyo) != 1 {
+		t.Fatalf("want 1 event in Tokyo week containing Jan 15, got %+v", weekTokyo)
+	}
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load location %q: %v", name, err)
+	}
+	return loc
+}
@@ -0,0 +1,227 @@
+package calendar
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidICS is returned when an imported .ics payload cannot be parsed.
+var ErrInvalidICS = errors.New("invalid icalendar data")
+
+const icsDateLayout = "20060102"
+
+// ExportICS renders every master event (recurring or not) that has at least
+// one occurrence in [from, to) as an RFC 5545 VCALENDAR. Recurring events are
+// emitted once, as a VEVENT carrying RRULE/EXDATE, not expanded.
+func (s *Service) ExportICS(ctx context.Context, userID int64, from, to time.Time) ([]byte, error) {
+	if userID <= 0 {
+		return nil, ErrInvalidUserID
+	}
+	candidates, err := s.store.ListInRange(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var masters []Event
+	for _, ev := range candidates {
+		if len(expand(ev, from, to)) > 0 {
+			masters = append(masters, ev)
+		}
+	}
+	sort.Slice(masters, func(i, j int) bool { return masters[i].ID < masters[j].ID })
+
+	var buf bytes.Buffer
+	buf.WriteString("BEGIN:VCALENDAR\r\n")
+	buf.WriteString("VERSION:2.0\r\n")
+	buf.WriteString("PRODID:-//calendar//EN\r\n")
+	for _, ev := range masters {
+		writeVEvent(&buf, ev)
+	}
+	buf.WriteString("END:VCALENDAR\r\n")
+	return buf.Bytes(), nil
+}
+
+func writeVEvent(buf *bytes.Buffer, ev Event) {
+	buf.WriteString("BEGIN:VEVENT\r\n")
+	fmt.Fprintf(buf, "UID:%s\r\n", ev.ID)
+	fmt.Fprintf(buf, "DTSTART;VALUE=DATE:%s\r\n", ev.Date.Format(icsDateLayout))
+	fmt.Fprintf(buf, "SUMMARY:%s\r\n", escapeText(ev.Text))
+	if ev.Recurrence != "" {
+		fmt.Fprintf(buf, "RRULE:%s\r\n", ev.Recurrence)
+	}
+	if len(ev.ExDates) > 0 {
+		dates := make([]string, len(ev.ExDates))
+		for i, d := range ev.ExDates {
+			dates[i] = d.Format(icsDateLayout)
+		}
+		fmt.Fprintf(buf, "EXDATE;VALUE=DATE:%s\r\n", strings.Join(dates, ","))
+	}
+	buf.WriteString("END:VEVENT\r\n")
+}
+
+// ImportICS parses a VCALENDAR payload and creates one event per VEVENT
+// block, returning the created events in file order.
+func (s *Service) ImportICS(ctx context.Context, userID int64, r io.Reader) ([]Event, error) {
+	if userID <= 0 {
+		return nil, ErrInvalidUserID
+	}
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []Event
+	var cur map[string][]string
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT":
+			cur = make(map[string][]string)
+		case line == "END:VEVENT":
+			if cur == nil {
+				continue
+			}
+			ev, err := s.createFromVEvent(ctx, userID, cur)
+			if err != nil {
+				return nil, err
+			}
+			created = append(created, ev)
+			cur = nil
+		case cur != nil:
+			name, _, value := parseProperty(line)
+			cur[name] = append(cur[name], value)
+		}
+	}
+	return created, nil
+}
+
+func (s *Service) createFromVEvent(ctx context.Context, userID int64, props map[string][]string) (Event, error) {
+	dtstarts := props["DTSTART"]
+	if len(dtstarts) == 0 {
+		return Event{}, ErrInvalidICS
+	}
+	dtstart, err := parseICSDate(dtstarts[0])
+	if err != nil {
+		return Event{}, ErrInvalidICS
+	}
+	var summary string
+	if len(props["SUMMARY"]) > 0 {
+		summary = unescapeText(props["SUMMARY"][0])
+	}
+	var recurrence string
+	if len(props["RRULE"]) > 0 {
+		recurrence = props["RRULE"][0]
+	}
+
+	ev, err := s.CreateEvent(ctx, userID, dtstart, time.Time{}, "", summary, recurrence)
+	if err != nil {
+		return Event{}, err
+	}
+
+	for _, raw := range props["EXDATE"] {
+		for _, part := range strings.Split(raw, ",") {
+			d, err := parseICSDate(part)
+			if err != nil {
+				continue
+			}
+			_ = s.DeleteEvent(ctx, occurrenceID(ev.ID, d), userID)
+		}
+	}
+	if len(props["EXDATE"]) > 0 {
+		ev = s.mustGet(ctx, userID, ev.ID)
+	}
+	return ev, nil
+}
+
+// mustGet returns the current stored state of an event, assuming it exists
+// (the caller just created or modified it under the same userID).
+func (s *Service) mustGet(ctx context.Context, userID int64, id string) Event {
+	ev, _ := s.store.Get(ctx, userID, id)
+	return ev
+}
+
+func parseICSDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 8 {
+		if t, err := time.Parse(icsDateLayout, s[:8]); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, ErrInvalidICS
+}
+
+// unfoldLines reads CRLF-delimited content and rejoins folded lines: a line
+// that starts with a space or tab is a continuation of the previous line.
+func unfoldLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		raw := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(raw, " ") || strings.HasPrefix(raw, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += raw[1:]
+			continue
+		}
+		lines = append(lines, raw)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// parseProperty splits a property line into its name, parameters, and value,
+// e.g. "DTSTART;VALUE=DATE:20240101" -> ("DTSTART", {"VALUE":"DATE"}, "20240101").
+func parseProperty(line string) (name string, params map[string]string, value string) {
+	colon := strings.IndexByte(line, ':')
+	if colon < 0 {
+		return line, nil, ""
+	}
+	head, value := line[:colon], line[colon+1:]
+	parts := strings.Split(head, ";")
+	name = strings.ToUpper(parts[0])
+	if len(parts) > 1 {
+		params = make(map[string]string, len(parts)-1)
+		for _, p := range parts[1:] {
+			if kv := strings.SplitN(p, "=", 2); len(kv) == 2 {
+				params[strings.ToUpper(kv[0])] = kv[1]
+			}
+		}
+	}
+	return name, params, value
+}
+
+func escapeText(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `,`, `\,`, `;`, `\;`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+func unescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			case ',':
+				b.WriteByte(',')
+			case ';':
+				b.WriteByte(';')
+			case '\\':
+				b.WriteByte('\\')
+			default:
+				b.WriteByte(s[i+1])
+			}
+			i++
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
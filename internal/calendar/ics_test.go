@@ -0,0 +1,75 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	s := NewService()
+	if _, err := s.CreateEvent(ctx, 1, mustDate(t, "2024-01-01"), time.Time{}, "", "Standup", "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateEvent(ctx, 1, mustDate(t, "2024-01-05"), time.Time{}, "", "One-off, with a comma", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := s.ExportICS(ctx, 1, mustDate(t, "2024-01-01"), mustDate(t, "2024-02-01"))
+	if err != nil {
+		t.Fatalf("ExportICS: %v", err)
+	}
+	if !strings.Contains(string(data), "BEGIN:VCALENDAR") || !strings.Contains(string(data), "RRULE:FREQ=WEEKLY") {
+		t.Fatalf("unexpected ics output:\n%s", data)
+	}
+
+	other := NewService()
+	events, err := other.ImportICS(ctx, 2, strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("ImportICS: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("want 2 imported masters, got %d: %+v", len(events), events)
+	}
+
+	jan, err := other.EventsForMonth(ctx, 2, mustDate(t, "2024-01-15"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jan) != 5 {
+		t.Fatalf("want 5 occurrences after import (4 recurring + 1 one-off), got %d: %+v", len(jan), jan)
+	}
+}
+
+func TestImportUnfoldsAndUnescapes(t *testing.T) {
+	raw := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:abc\r\n" +
+		"DTSTART;VALUE=DATE:20240115\r\n" +
+		"SUMMARY:Line one\\, with a\r\n" +
+		" continuation\\nsecond line\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	s := NewService()
+	events, err := s.ImportICS(ctx, 1, strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("ImportICS: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("want 1 event, got %d", len(events))
+	}
+	want := "Line one, with acontinuation\nsecond line"
+	if events[0].Text != want {
+		t.Fatalf("got %q, want %q", events[0].Text, want)
+	}
+}
+
+func TestImportInvalidICS(t *testing.T) {
+	s := NewService()
+	_, err := s.ImportICS(ctx, 1, strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:No start\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"))
+	if err != ErrInvalidICS {
+		t.Fatalf("want ErrInvalidICS, got %v", err)
+	}
+}
@@ -0,0 +1,360 @@
+package calendar
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRecurrence is returned when a Recurrence string cannot be parsed
+// as an RFC 5545 RRULE.
+var ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+
+// ByDay is a BYDAY entry, e.g. "MO" or "2MO" (2nd Monday) or "-1FR" (last Friday).
+type ByDay struct {
+	Ordinal int // 0 means "every occurrence of this weekday"
+	Weekday time.Weekday
+}
+
+// RRule is a parsed RFC 5545 recurrence rule (the subset needed for
+// FREQ=DAILY/WEEKLY/MONTHLY/YEARLY).
+type RRule struct {
+	Freq       string // DAILY, WEEKLY, MONTHLY, YEARLY
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []ByDay
+	ByMonthDay []int
+	ByMonth    []int
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseRRule parses an RFC 5545 RRULE value, e.g.
+// "FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20241231T000000Z".
+func ParseRRule(s string) (RRule, error) {
+	rule := RRule{Interval: 1}
+	if strings.TrimSpace(s) == "" {
+		return RRule{}, ErrInvalidRecurrence
+	}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return RRule{}, ErrInvalidRecurrence
+		}
+		key, val := strings.ToUpper(kv[0]), kv[1]
+		switch key {
+		case "FREQ":
+			switch val {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				rule.Freq = val
+			default:
+				return RRule{}, ErrInvalidRecurrence
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n <= 0 {
+				return RRule{}, ErrInvalidRecurrence
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n <= 0 {
+				return RRule{}, ErrInvalidRecurrence
+			}
+			rule.Count = n
+		case "UNTIL":
+			t, err := parseICalTime(val)
+			if err != nil {
+				return RRule{}, ErrInvalidRecurrence
+			}
+			rule.Until = t
+		case "BYDAY":
+			for _, d := range strings.Split(val, ",") {
+				bd, err := parseByDay(d)
+				if err != nil {
+					return RRule{}, err
+				}
+				rule.ByDay = append(rule.ByDay, bd)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n == 0 || n > 31 || n < -31 {
+					return RRule{}, ErrInvalidRecurrence
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, d := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n < 1 || n > 12 {
+					return RRule{}, ErrInvalidRecurrence
+				}
+				rule.ByMonth = append(rule.ByMonth, n)
+			}
+		}
+	}
+	if rule.Freq == "" {
+		return RRule{}, ErrInvalidRecurrence
+	}
+	return rule, nil
+}
+
+func parseByDay(s string) (ByDay, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 {
+		return ByDay{}, ErrInvalidRecurrence
+	}
+	wd, ok := weekdayCodes[s[len(s)-2:]]
+	if !ok {
+		return ByDay{}, ErrInvalidRecurrence
+	}
+	ordinal := 0
+	if prefix := s[:len(s)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 {
+			return ByDay{}, ErrInvalidRecurrence
+		}
+		ordinal = n
+	}
+	return ByDay{Ordinal: ordinal, Weekday: wd}, nil
+}
+
+func parseICalTime(s string) (time.Time, error) {
+	if strings.HasSuffix(s, "Z") {
+		return time.Parse("20060102T150405Z", s)
+	}
+	return time.ParseInLocation("20060102T150405", s, time.UTC)
+}
+
+// Expand returns every occurrence of the rule anchored at dtstart that falls
+// within [rangeStart, rangeEnd), excluding any date present in exdates.
+// Candidates before dtstart are skipped; dtstart itself is an occurrence
+// only if it matches the rule.
+func (r RRule) Expand(dtstart, rangeStart, rangeEnd time.Time, exdates []time.Time) []time.Time {
+	excluded := make(map[string]bool, len(exdates))
+	for _, d := range exdates {
+		excluded[d.Format("2006-01-02")] = true
+	}
+
+	interval := r.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+
+	var res []time.Time
+	matched := 0
+	const maxPeriods = 100000
+	for period := 0; period < maxPeriods; period++ {
+		candidates := r.periodCandidates(dtstart, period*interval)
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+
+		for _, c := range candidates {
+			if c.Before(dtstart) {
+				continue
+			}
+			if !r.Until.IsZero() && c.After(r.Until) {
+				continue
+			}
+			matched++
+			if r.Count > 0 && matched > r.Count {
+				return res
+			}
+			if !c.Before(rangeStart) && c.Before(rangeEnd) && !excluded[c.Format("2006-01-02")] {
+				res = append(res, c)
+			}
+		}
+
+		if r.Count == 0 {
+			bound := rangeEnd
+			if !r.Until.IsZero() && r.Until.Before(bound) {
+				bound = r.Until
+			}
+			if pastBound(dtstart, r.Freq, period*interval, bound) {
+				break
+			}
+		}
+	}
+	return res
+}
+
+// pastBound reports whether every later period is guaranteed to fall after
+// bound, letting Expand stop early. offset is the FREQ-unit distance from
+// dtstart to the period just checked (period*interval).
+func pastBound(dtstart time.Time, freq string, offset int, bound time.Time) bool {
+	var periodStart time.Time
+	switch freq {
+	case "DAILY":
+		periodStart = dtstart.AddDate(0, 0, offset)
+	case "WEEKLY":
+		periodStart = dtstart.AddDate(0, 0, offset*7)
+	case "MONTHLY":
+		y, m := addMonths(dtstart.Year(), dtstart.Month(), offset)
+		periodStart = time.Date(y, m, 1, 0, 0, 0, 0, dtstart.Location())
+	case "YEARLY":
+		periodStart = time.Date(dtstart.Year()+offset, dtstart.Month(), 1, 0, 0, 0, 0, dtstart.Location())
+	}
+	return periodStart.After(bound)
+}
+
+// periodCandidates returns the candidate occurrence dates for the period that
+// starts `offset` FREQ-units after dtstart (offset already includes INTERVAL).
+func (r RRule) periodCandidates(dtstart time.Time, offset int) []time.Time {
+	switch r.Freq {
+	case "DAILY":
+		return []time.Time{dtstart.AddDate(0, 0, offset)}
+	case "WEEKLY":
+		return r.weeklyCandidates(dtstart, offset*7)
+	case "MONTHLY":
+		year, month := addMonths(dtstart.Year(), dtstart.Month(), offset)
+		return r.monthCandidates(dtstart, year, month)
+	case "YEARLY":
+		return r.yearCandidates(dtstart, dtstart.Year()+offset)
+	default:
+		return nil
+	}
+}
+
+func (r RRule) weeklyCandidates(dtstart time.Time, offsetDays int) []time.Time {
+	weekStart := mondayOf(dtstart).AddDate(0, 0, offsetDays)
+	if len(r.ByDay) == 0 {
+		return []time.Time{atClock(weekStart.AddDate(0, 0, isoOffset(dtstart.Weekday())), dtstart)}
+	}
+	var res []time.Time
+	for _, bd := range r.ByDay {
+		res = append(res, atClock(weekStart.AddDate(0, 0, isoOffset(bd.Weekday)), dtstart))
+	}
+	return res
+}
+
+func (r RRule) monthCandidates(dtstart time.Time, year int, month time.Month) []time.Time {
+	var res []time.Time
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, md := range r.ByMonthDay {
+			if d, ok := dayOfMonth(year, month, md, dtstart.Location()); ok {
+				res = append(res, atClock(d, dtstart))
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, bd := range r.ByDay {
+			if bd.Ordinal == 0 {
+				for _, d := range allWeekdaysOfMonth(year, month, bd.Weekday, dtstart.Location()) {
+					res = append(res, atClock(d, dtstart))
+				}
+				continue
+			}
+			if d, ok := nthWeekdayOfMonth(year, month, bd.Weekday, bd.Ordinal, dtstart.Location()); ok {
+				res = append(res, atClock(d, dtstart))
+			}
+		}
+	default:
+		if d, ok := dayOfMonth(year, month, dtstart.Day(), dtstart.Location()); ok {
+			res = append(res, atClock(d, dtstart))
+		}
+	}
+	return res
+}
+
+func (r RRule) yearCandidates(dtstart time.Time, year int) []time.Time {
+	months := r.ByMonth
+	if len(months) == 0 {
+		months = []int{int(dtstart.Month())}
+	}
+	var res []time.Time
+	for _, m := range months {
+		res = append(res, r.monthCandidates(dtstart, year, time.Month(m))...)
+	}
+	return res
+}
+
+// addMonths advances (year, month) by n months using pure calendar
+// arithmetic, avoiding time.AddDate's day-overflow normalization (e.g.
+// Jan 31 + 1 month must land on February, not roll into March).
+func addMonths(year int, month time.Month, n int) (int, time.Month) {
+	total := int(month) - 1 + n
+	y := year + total/12
+	m := total % 12
+	if m < 0 {
+		m += 12
+		y--
+	}
+	return y, time.Month(m + 1)
+}
+
+func mondayOf(d time.Time) time.Time {
+	offset := isoOffset(d.Weekday())
+	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, d.Location()).AddDate(0, 0, -offset)
+}
+
+// isoOffset returns how many days after Monday the given weekday falls.
+func isoOffset(wd time.Weekday) int {
+	if wd == time.Sunday {
+		return 6
+	}
+	return int(wd) - 1
+}
+
+func atClock(d, clockFrom time.Time) time.Time {
+	return time.Date(d.Year(), d.Month(), d.Day(),
+		clockFrom.Hour(), clockFrom.Minute(), clockFrom.Second(), 0, clockFrom.Location())
+}
+
+// dayOfMonth resolves a BYMONTHDAY value (1-31, or negative counting from the
+// end of the month) to a concrete date, reporting false if the month is too
+// short to contain it.
+func dayOfMonth(year int, month time.Month, day int, loc *time.Location) (time.Time, bool) {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	if day < 0 {
+		day = lastDay + day + 1
+	}
+	if day < 1 || day > lastDay {
+		return time.Time{}, false
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc), true
+}
+
+// nthWeekdayOfMonth resolves a BYDAY ordinal (e.g. 2MO, -1FR) to a concrete
+// date, reporting false if the month doesn't have that many occurrences.
+func nthWeekdayOfMonth(year int, month time.Month, wd time.Weekday, ordinal int, loc *time.Location) (time.Time, bool) {
+	matches := weekdaysOfMonth(year, month, wd, loc)
+	idx := ordinal - 1
+	if ordinal < 0 {
+		idx = len(matches) + ordinal
+	}
+	if idx < 0 || idx >= len(matches) {
+		return time.Time{}, false
+	}
+	return time.Date(year, month, matches[idx], 0, 0, 0, 0, loc), true
+}
+
+// allWeekdaysOfMonth returns every date in the month that falls on wd.
+func allWeekdaysOfMonth(year int, month time.Month, wd time.Weekday, loc *time.Location) []time.Time {
+	var res []time.Time
+	for _, day := range weekdaysOfMonth(year, month, wd, loc) {
+		res = append(res, time.Date(year, month, day, 0, 0, 0, 0, loc))
+	}
+	return res
+}
+
+func weekdaysOfMonth(year int, month time.Month, wd time.Weekday, loc *time.Location) []int {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	var matches []int
+	for day := 1; day <= lastDay; day++ {
+		if time.Date(year, month, day, 0, 0, 0, 0, loc).Weekday() == wd {
+			matches = append(matches, day)
+		}
+	}
+	return matches
+}
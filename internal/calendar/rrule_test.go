@@ -0,0 +1,64 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRRuleInvalid(t *testing.T) {
+	cases := []string{"", "FREQ=HOURLY", "BYDAY=MO", "FREQ=WEEKLY;COUNT=x"}
+	for _, c := range cases {
+		if _, err := ParseRRule(c); err == nil {
+			t.Errorf("ParseRRule(%q): want error, got nil", c)
+		}
+	}
+}
+
+func TestExpandMonthlyByDayOrdinal(t *testing.T) {
+	// Second Monday of every month, starting Jan 2024.
+	rule, err := ParseRRule("FREQ=MONTHLY;BYDAY=2MO;COUNT=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dtstart := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	got := rule.Expand(dtstart, dtstart, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), nil)
+	want := []string{"2024-01-08", "2024-02-12", "2024-03-11"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d occurrences, want %d: %v", len(got), len(want), got)
+	}
+	for i, d := range got {
+		if d.Format("2006-01-02") != want[i] {
+			t.Errorf("occurrence %d: got %s, want %s", i, d.Format("2006-01-02"), want[i])
+		}
+	}
+}
+
+func TestExpandCountStopsBeforeRangeEnd(t *testing.T) {
+	rule, err := ParseRRule("FREQ=DAILY;COUNT=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dtstart := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	got := rule.Expand(dtstart, dtstart, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), nil)
+	if len(got) != 2 {
+		t.Fatalf("want 2 occurrences, got %d: %v", len(got), got)
+	}
+}
+
+func TestExpandExcludesExdates(t *testing.T) {
+	rule, err := ParseRRule("FREQ=DAILY;COUNT=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dtstart := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	exdates := []time.Time{time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}
+	got := rule.Expand(dtstart, dtstart, time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), exdates)
+	if len(got) != 3 {
+		t.Fatalf("want 3 occurrences, got %d: %v", len(got), got)
+	}
+	for _, d := range got {
+		if d.Format("2006-01-02") == "2024-01-02" {
+			t.Fatalf("excluded date present: %v", got)
+		}
+	}
+}
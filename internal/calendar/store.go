@@ -0,0 +1,144 @@
+package calendar
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store persists events for the calendar service. Implementations must be
+// safe for concurrent use. A userID of 0 passed to Get or Delete means
+// "match any user" (event IDs are globally unique UUIDs); Service relies on
+// this for the admin-style delete-by-id-only path. ctx carries the
+// originating request's deadline/cancellation down to the backend.
+type Store interface {
+	Create(ctx context.Context, ev Event) error
+	Update(ctx context.Context, ev Event) error
+	Delete(ctx context.Context, userID int64, id string) error
+	Get(ctx context.Context, userID int64, id string) (Event, error)
+	ListInRange(ctx context.Context, userID int64, from, to time.Time) ([]Event, error)
+}
+
+// candidateInRange reports whether ev might contribute an occurrence to
+// [from, to): recurring masters always qualify since only the RRule
+// expander can tell whether they actually produce one; non-recurring events
+// qualify only if their own date falls in range.
+func candidateInRange(ev Event, from, to time.Time) bool {
+	if ev.Recurrence != "" {
+		return true
+	}
+	return !ev.StartAt.Before(from) && ev.StartAt.Before(to)
+}
+
+// MemoryStore is the default Store: everything lives in process memory and
+// is lost on restart.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	byUser map[int64]map[string]Event
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{byUser: make(map[int64]map[string]Event)}
+}
+
+func (m *MemoryStore) Create(ctx context.Context, ev Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.byUser[ev.UserID]; !ok {
+		m.byUser[ev.UserID] = make(map[string]Event)
+	}
+	m.byUser[ev.UserID][ev.ID] = ev
+	return nil
+}
+
+func (m *MemoryStore) Update(ctx context.Context, ev Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	userEvents, ok := m.byUser[ev.UserID]
+	if !ok {
+		return ErrNotFound
+	}
+	if _, ok := userEvents[ev.ID]; !ok {
+		return ErrNotFound
+	}
+	userEvents[ev.ID] = ev
+	return nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, userID int64, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if userID > 0 {
+		userEvents, ok := m.byUser[userID]
+		if !ok {
+			return ErrNotFound
+		}
+		if _, ok := userEvents[id]; !ok {
+			return ErrNotFound
+		}
+		delete(userEvents, id)
+		if len(userEvents) == 0 {
+			delete(m.byUser, userID)
+		}
+		return nil
+	}
+	for uid, userEvents := range m.byUser {
+		if _, ok := userEvents[id]; ok {
+			delete(userEvents, id)
+			if len(userEvents) == 0 {
+				delete(m.byUser, uid)
+			}
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *MemoryStore) Get(ctx context.Context, userID int64, id string) (Event, error) {
+	if err := ctx.Err(); err != nil {
+		return Event{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if userID > 0 {
+		ev, ok := m.byUser[userID][id]
+		if !ok {
+			return Event{}, ErrNotFound
+		}
+		return ev, nil
+	}
+	for _, userEvents := range m.byUser {
+		if ev, ok := userEvents[id]; ok {
+			return ev, nil
+		}
+	}
+	return Event{}, ErrNotFound
+}
+
+func (m *MemoryStore) ListInRange(ctx context.Context, userID int64, from, to time.Time) ([]Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	userEvents, ok := m.byUser[userID]
+	if !ok {
+		return []Event{}, nil
+	}
+	res := make([]Event, 0, len(userEvents))
+	for _, ev := range userEvents {
+		if candidateInRange(ev, from, to) {
+			res = append(res, ev)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,155 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// BoltStore persists events in a BoltDB file, bucketed per user so that
+// ListInRange only has to scan one user's bucket.
+type BoltStore struct {
+	db *bbolt.DB
+}
+
+func NewBoltStore(db *bbolt.DB) *BoltStore {
+	return &BoltStore{db: db}
+}
+
+func userBucketName(userID int64) []byte {
+	return []byte(strconv.FormatInt(userID, 10))
+}
+
+func (b *BoltStore) Create(ctx context.Context, ev Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(userBucketName(ev.UserID))
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(ev.ID), data)
+	})
+}
+
+func (b *BoltStore) Update(ctx context.Context, ev Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(userBucketName(ev.UserID))
+		if bucket == nil || bucket.Get([]byte(ev.ID)) == nil {
+			return ErrNotFound
+		}
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(ev.ID), data)
+	})
+}
+
+func (b *BoltStore) Delete(ctx context.Context, userID int64, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if userID > 0 {
+		return b.db.Update(func(tx *bbolt.Tx) error {
+			bucket := tx.Bucket(userBucketName(userID))
+			if bucket == nil || bucket.Get([]byte(id)) == nil {
+				return ErrNotFound
+			}
+			return bucket.Delete([]byte(id))
+		})
+	}
+
+	found := false
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.ForEach(func(_ []byte, bucket *bbolt.Bucket) error {
+			if bucket.Get([]byte(id)) == nil {
+				return nil
+			}
+			found = true
+			return bucket.Delete([]byte(id))
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (b *BoltStore) Get(ctx context.Context, userID int64, id string) (Event, error) {
+	if err := ctx.Err(); err != nil {
+		return Event{}, err
+	}
+	var ev Event
+	found := false
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		if userID > 0 {
+			bucket := tx.Bucket(userBucketName(userID))
+			if bucket == nil {
+				return nil
+			}
+			data := bucket.Get([]byte(id))
+			if data == nil {
+				return nil
+			}
+			found = true
+			return json.Unmarshal(data, &ev)
+		}
+		return tx.ForEach(func(_ []byte, bucket *bbolt.Bucket) error {
+			if found {
+				return nil
+			}
+			data := bucket.Get([]byte(id))
+			if data == nil {
+				return nil
+			}
+			found = true
+			return json.Unmarshal(data, &ev)
+		})
+	})
+	if err != nil {
+		return Event{}, err
+	}
+	if !found {
+		return Event{}, ErrNotFound
+	}
+	return ev, nil
+}
+
+func (b *BoltStore) ListInRange(ctx context.Context, userID int64, from, to time.Time) ([]Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var res []Event
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(userBucketName(userID))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, data []byte) error {
+			var ev Event
+			if err := json.Unmarshal(data, &ev); err != nil {
+				return err
+			}
+			if candidateInRange(ev, from, to) {
+				res = append(res, ev)
+			}
+			return nil
+		})
+	})
+	return res, err
+}
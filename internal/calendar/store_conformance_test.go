@@ -0,0 +1,82 @@
+package calendar
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"go.etcd.io/bbolt"
+)
+
+// testStoreConformance runs the same behavioral suite against any Store
+// implementation, so MemoryStore/SQLStore/BoltStore are all held to the same
+// contract.
+func testStoreConformance(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+
+	ev := Event{ID: "a1", UserID: 1, Date: mustDate(t, "2024-01-01"), StartAt: mustDate(t, "2024-01-01"), Text: "Standup"}
+	if err := store.Create(ctx, ev); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	got, err := store.Get(ctx, 1, "a1")
+	if err != nil || got.Text != "Standup" {
+		t.Fatalf("Get: %+v, %v", got, err)
+	}
+
+	ev.Text = "Standup (updated)"
+	if err := store.Update(ctx, ev); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got, err := store.Get(ctx, 1, "a1"); err != nil || got.Text != "Standup (updated)" {
+		t.Fatalf("Get after update: %+v, %v", got, err)
+	}
+
+	if err := store.Update(ctx, Event{ID: "missing", UserID: 1, Date: ev.Date, Text: "x"}); err != ErrNotFound {
+		t.Fatalf("Update missing: want ErrNotFound, got %v", err)
+	}
+
+	listed, err := store.ListInRange(ctx, 1, mustDate(t, "2024-01-01"), mustDate(t, "2024-01-02"))
+	if err != nil || len(listed) != 1 {
+		t.Fatalf("ListInRange: %+v, %v", listed, err)
+	}
+
+	if err := store.Delete(ctx, 1, "a1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, 1, "a1"); err != ErrNotFound {
+		t.Fatalf("Get after delete: want ErrNotFound, got %v", err)
+	}
+	if err := store.Delete(ctx, 1, "a1"); err != ErrNotFound {
+		t.Fatalf("Delete missing: want ErrNotFound, got %v", err)
+	}
+}
+
+func TestMemoryStoreConformance(t *testing.T) {
+	testStoreConformance(t, NewMemoryStore())
+}
+
+func TestSQLStoreConformance(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	store, err := NewSQLStore(db, "sqlite")
+	if err != nil {
+		t.Fatalf("NewSQLStore: %v", err)
+	}
+	testStoreConformance(t, store)
+}
+
+func TestBoltStoreConformance(t *testing.T) {
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "events.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("open bolt: %v", err)
+	}
+	defer db.Close()
+	testStoreConformance(t, NewBoltStore(db))
+}
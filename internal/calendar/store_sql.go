@@ -0,0 +1,213 @@
+package calendar
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+const timeLayout = time.RFC3339Nano
+
+// SQLStore persists events in any database/sql driver (SQLite or Postgres
+// are the ones wired up in cmd/calendar/main.go). Callers open db with the matching
+// driver and pass its dialect so SQLStore can rebind placeholders.
+type SQLStore struct {
+	db      *sql.DB
+	dialect string // "sqlite" or "postgres"
+}
+
+// NewSQLStore opens the events table against db, creating it if needed.
+func NewSQLStore(db *sql.DB, dialect string) (*SQLStore, error) {
+	s := &SQLStore{db: db, dialect: dialect}
+	if _, err := db.Exec(s.rebind(`CREATE TABLE IF NOT EXISTS events (
+		id TEXT PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		start_at TIMESTAMP NOT NULL,
+		end_at TIMESTAMP,
+		timezone TEXT NOT NULL DEFAULT '',
+		text TEXT NOT NULL,
+		recurrence TEXT NOT NULL DEFAULT '',
+		exdates TEXT NOT NULL DEFAULT ''
+	)`)); err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(s.rebind(`CREATE INDEX IF NOT EXISTS idx_events_user_start ON events (user_id, start_at)`)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// rebind rewrites "?" placeholders to Postgres-style "$1, $2, ...".
+func (s *SQLStore) rebind(query string) string {
+	if s.dialect != "postgres" {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func exdatesToString(dates []time.Time) string {
+	if len(dates) == 0 {
+		return ""
+	}
+	parts := make([]string, len(dates))
+	for i, d := range dates {
+		parts[i] = d.Format(dateLayout)
+	}
+	return strings.Join(parts, ",")
+}
+
+func exdatesFromString(s string) []time.Time {
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	dates := make([]time.Time, 0, len(parts))
+	for _, p := range parts {
+		if d, err := time.Parse(dateLayout, p); err == nil {
+			dates = append(dates, d)
+		}
+	}
+	return dates
+}
+
+// endAtParam turns a possibly-zero EndAt into a driver value, storing NULL
+// when the event has no explicit end.
+func endAtParam(endAt time.Time) any {
+	if endAt.IsZero() {
+		return nil
+	}
+	return endAt.UTC().Format(timeLayout)
+}
+
+func (s *SQLStore) Create(ctx context.Context, ev Event) error {
+	_, err := s.db.ExecContext(ctx, s.rebind(`INSERT INTO events (id, user_id, start_at, end_at, timezone, text, recurrence, exdates) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+		ev.ID, ev.UserID, ev.StartAt.UTC().Format(timeLayout), endAtParam(ev.EndAt), ev.Timezone, ev.Text, ev.Recurrence, exdatesToString(ev.ExDates))
+	return err
+}
+
+func (s *SQLStore) Update(ctx context.Context, ev Event) error {
+	res, err := s.db.ExecContext(ctx, s.rebind(`UPDATE events SET start_at=?, end_at=?, timezone=?, text=?, recurrence=?, exdates=? WHERE id=? AND user_id=?`),
+		ev.StartAt.UTC().Format(timeLayout), endAtParam(ev.EndAt), ev.Timezone, ev.Text, ev.Recurrence, exdatesToString(ev.ExDates), ev.ID, ev.UserID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *SQLStore) Delete(ctx context.Context, userID int64, id string) error {
+	var res sql.Result
+	var err error
+	if userID > 0 {
+		res, err = s.db.ExecContext(ctx, s.rebind(`DELETE FROM events WHERE id=? AND user_id=?`), id, userID)
+	} else {
+		res, err = s.db.ExecContext(ctx, s.rebind(`DELETE FROM events WHERE id=?`), id)
+	}
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// scanEvent decodes the common (start_at, end_at, timezone, text,
+// recurrence, exdates) column set shared by Get and ListInRange, resolving
+// StartAt/EndAt back into the event's own timezone and recomputing Date.
+func scanEvent(id string, userID int64, startAtStr string, endAtStr sql.NullString, timezone, text, recurrence, exdatesStr string) (Event, error) {
+	startAt, err := time.Parse(timeLayout, startAtStr)
+	if err != nil {
+		return Event{}, err
+	}
+	loc := EventLocation(timezone)
+	startAt = startAt.In(loc)
+
+	var endAt time.Time
+	if endAtStr.Valid && endAtStr.String != "" {
+		endAt, err = time.Parse(timeLayout, endAtStr.String)
+		if err != nil {
+			return Event{}, err
+		}
+		endAt = endAt.In(loc)
+	}
+
+	return Event{
+		ID:         id,
+		UserID:     userID,
+		Date:       normalizeDate(startAt),
+		StartAt:    startAt,
+		EndAt:      endAt,
+		Timezone:   timezone,
+		Text:       text,
+		Recurrence: recurrence,
+		ExDates:    exdatesFromString(exdatesStr),
+	}, nil
+}
+
+func (s *SQLStore) Get(ctx context.Context, userID int64, id string) (Event, error) {
+	query := `SELECT id, user_id, start_at, end_at, timezone, text, recurrence, exdates FROM events WHERE id=?`
+	args := []any{id}
+	if userID > 0 {
+		query += ` AND user_id=?`
+		args = append(args, userID)
+	}
+	var evID, timezone, text, recurrence, exdatesStr, startAtStr string
+	var evUserID int64
+	var endAtStr sql.NullString
+	err := s.db.QueryRowContext(ctx, s.rebind(query), args...).
+		Scan(&evID, &evUserID, &startAtStr, &endAtStr, &timezone, &text, &recurrence, &exdatesStr)
+	if err == sql.ErrNoRows {
+		return Event{}, ErrNotFound
+	}
+	if err != nil {
+		return Event{}, err
+	}
+	return scanEvent(evID, evUserID, startAtStr, endAtStr, timezone, text, recurrence, exdatesStr)
+}
+
+func (s *SQLStore) ListInRange(ctx context.Context, userID int64, from, to time.Time) ([]Event, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT id, user_id, start_at, end_at, timezone, text, recurrence, exdates FROM events
+		WHERE user_id=? AND (recurrence != '' OR (start_at >= ? AND start_at < ?))`),
+		userID, from.UTC().Format(timeLayout), to.UTC().Format(timeLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []Event
+	for rows.Next() {
+		var evID, timezone, text, recurrence, exdatesStr, startAtStr string
+		var evUserID int64
+		var endAtStr sql.NullString
+		if err := rows.Scan(&evID, &evUserID, &startAtStr, &endAtStr, &timezone, &text, &recurrence, &exdatesStr); err != nil {
+			return nil, err
+		}
+		ev, err := scanEvent(evID, evUserID, startAtStr, endAtStr, timezone, text, recurrence, exdatesStr)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, ev)
+	}
+	return res, rows.Err()
+}
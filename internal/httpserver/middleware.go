@@ -0,0 +1,225 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Middleware wraps an http.Handler with additional behavior.
+type Middleware func(http.Handler) http.Handler
+
+// Chain composes middlewares into one, with the first listed running
+// outermost: it sees the request first and the response last.
+func Chain(mws ...Middleware) Middleware {
+	return func(final http.Handler) http.Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			final = mws[i](final)
+		}
+		return final
+	}
+}
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	userIDKey
+)
+
+// LoggingMiddleware logs each request's request ID, method, path, status,
+// and latency.
+func LoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		log.Printf("%s %s %s %d %s", RequestIDFromContext(r.Context()), r.Method, r.URL.Path, sw.status, time.Since(start))
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// RequestID reads X-Request-ID off the incoming request, generating one if
+// absent, and stores it in both the request context and the response header
+// so it can be correlated across logs and client retries.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" {
+			id = uuid.NewString()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+// RequestIDFromContext returns the request ID stashed by RequestID, or "" if
+// none is present.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// Recover catches panics from downstream handlers, logs the stack trace, and
+// responds with a generic 500 instead of taking down the process.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				log.Printf("panic handling %s %s: %v\n%s", r.Method, r.URL.Path, rec, debug.Stack())
+				internalError(w, "internal server error")
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Auth validates an HMAC-signed bearer token of the form
+// "<user_id>.<hex hmac-sha256(secret, user_id)>" and injects the
+// authenticated user ID into the request context, so handlers read it via
+// UserIDFromContext instead of trusting a client-supplied user_id field.
+func Auth(secret []byte) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isHealthCheckPath(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" {
+				writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+				return
+			}
+			userID, err := verifyAuthToken(secret, token)
+			if err != nil {
+				writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "invalid bearer token"})
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userIDKey, userID)))
+		})
+	}
+}
+
+func verifyAuthToken(secret []byte, token string) (int64, error) {
+	userIDStr, sigHex, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, errors.New("malformed token")
+	}
+	sig, err := hex.DecodeString(sigHex)
+	if err != nil {
+		return 0, errors.New("malformed signature")
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(userIDStr))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return 0, errors.New("signature mismatch")
+	}
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		return 0, errors.New("invalid user id")
+	}
+	return userID, nil
+}
+
+// UserIDFromContext returns the authenticated user ID injected by Auth.
+func UserIDFromContext(ctx context.Context) (int64, bool) {
+	userID, ok := ctx.Value(userIDKey).(int64)
+	return userID, ok
+}
+
+// isHealthCheckPath reports whether path is one of the unauthenticated
+// liveness/readiness probes, which Auth and RateLimit both let through.
+func isHealthCheckPath(path string) bool {
+	return path == "/healthz" || path == "/readyz"
+}
+
+// RateLimit enforces a per-authenticated-user token bucket (burst capacity,
+// refilling at rps tokens/second), rejecting over-limit requests with 429
+// and a Retry-After header. It must sit behind Auth in the chain, since it
+// keys buckets by the authenticated user ID.
+func RateLimit(rps float64, burst int) Middleware {
+	limiter := &rateLimiter{rps: rps, burst: float64(burst), buckets: make(map[int64]*tokenBucket)}
+	return limiter.middleware
+}
+
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[int64]*tokenBucket
+	rps     float64
+	burst   float64
+}
+
+func (l *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isHealthCheckPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, ok := UserIDFromContext(r.Context())
+		if !ok {
+			internalError(w, "rate limit requires an authenticated request")
+			return
+		}
+		if allowed, retryAfter := l.bucketFor(userID).take(); !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			writeJSON(w, http.StatusTooManyRequests, errorResponse{Error: "rate limit exceeded"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *rateLimiter) bucketFor(userID int64) *tokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[userID]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, capacity: l.burst, rate: l.rps, last: time.Now()}
+		l.buckets[userID] = b
+	}
+	return b
+}
+
+// tokenBucket refills continuously at rate tokens/second up to capacity;
+// each request consumes one token.
+type tokenBucket struct {
+	mu       sync.Mutex
+	tokens   float64
+	capacity float64
+	rate     float64
+	last     time.Time
+}
+
+func (b *tokenBucket) take() (allowed bool, retryAfter time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
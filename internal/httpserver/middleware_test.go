@@ -0,0 +1,183 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func signToken(secret []byte, userID int64) string {
+	userIDStr := strconv.FormatInt(userID, 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(userIDStr))
+	return userIDStr + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthAcceptsValidToken(t *testing.T) {
+	secret := []byte("s3cr3t")
+	var gotUserID int64
+	var gotOK bool
+	h := Auth(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID, gotOK = UserIDFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/events_for_day", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(secret, 42))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d", rec.Code)
+	}
+	if !gotOK || gotUserID != 42 {
+		t.Fatalf("want authenticated user 42, got %d (ok=%v)", gotUserID, gotOK)
+	}
+}
+
+func TestAuthRejectsTamperedSignature(t *testing.T) {
+	secret := []byte("s3cr3t")
+	h := Auth(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	token := signToken(secret, 42)
+	req := httptest.NewRequest(http.MethodGet, "/events_for_day", nil)
+	req.Header.Set("Authorization", "Bearer "+token[:len(token)-1]+"0")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("want 401 for tampered signature, got %d", rec.Code)
+	}
+}
+
+func TestAuthRejectsMalformedToken(t *testing.T) {
+	secret := []byte("s3cr3t")
+	h := Auth(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, token := range []string{"", "no-dot-here", "abc.nothex", "-1." + hex.EncodeToString(hmac.New(sha256.New, secret).Sum(nil))} {
+		req := httptest.NewRequest(http.MethodGet, "/events_for_day", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("token %q: want 401, got %d", token, rec.Code)
+		}
+	}
+}
+
+func TestAuthLetsHealthChecksThrough(t *testing.T) {
+	h := Auth([]byte("s3cr3t"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for _, path := range []string{"/healthz", "/readyz"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: want health check to bypass auth, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestRateLimitAllowsBurstThenDeniesThenRefills(t *testing.T) {
+	limiter := &rateLimiter{rps: 1000, burst: 2, buckets: make(map[int64]*tokenBucket)}
+	h := limiter.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	newReq := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/events_for_day", nil)
+		return req.WithContext(context.WithValue(req.Context(), userIDKey, int64(7)))
+	}
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newReq())
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: want 200 within burst, got %d", i, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newReq())
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("want 429 after burst exhausted, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("want Retry-After header on 429 response")
+	}
+
+	// rps=1000 refills a token well within a test's wall-clock budget.
+	deadline := time.Now().Add(50 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, newReq())
+		if rec.Code == http.StatusOK {
+			return
+		}
+	}
+	t.Fatalf("want bucket to refill and allow a request again, still got %d", rec.Code)
+}
+
+func TestRateLimitRequiresAuthenticatedRequest(t *testing.T) {
+	h := RateLimit(1, 1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/events_for_day", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("want 500 for unauthenticated request reaching RateLimit, got %d", rec.Code)
+	}
+}
+
+func TestRecoverTurnsPanicIntoJSON500(t *testing.T) {
+	h := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/events_for_day", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("want 500 after recovered panic, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("want JSON error body after recovered panic, got Content-Type %q", ct)
+	}
+}
+
+func TestRequestIDGeneratesWhenAbsentAndEchoesWhenProvided(t *testing.T) {
+	var fromCtx string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromCtx = RequestIDFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/events_for_day", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if fromCtx == "" || rec.Header().Get("X-Request-ID") != fromCtx {
+		t.Fatalf("want generated request ID echoed in response header, got ctx=%q header=%q", fromCtx, rec.Header().Get("X-Request-ID"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/events_for_day", nil)
+	req.Header.Set("X-Request-ID", "client-supplied-id")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if fromCtx != "client-supplied-id" {
+		t.Fatalf("want client-supplied request ID preserved, got %q", fromCtx)
+	}
+	if got := rec.Header().Get("X-Request-ID"); got != "client-supplied-id" {
+		t.Fatalf("want client-supplied request ID echoed in response header, got %q", got)
+	}
+}
@@ -3,9 +3,10 @@ package httpserver
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
-	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"calendar/internal/calendar"
@@ -13,12 +14,20 @@ import (
 
 type Server struct {
 	Svc *calendar.Service
+
+	shuttingDown atomic.Bool
 }
 
 func New(svc *calendar.Service) *Server {
 	return &Server{Svc: svc}
 }
 
+// SetShuttingDown flips /healthz and /readyz to 503 so load balancers stop
+// routing traffic while in-flight requests drain.
+func (s *Server) SetShuttingDown() {
+	s.shuttingDown.Store(true)
+}
+
 func (s *Server) Router() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/create_event", s.handleCreateEvent)
@@ -27,9 +36,33 @@ func (s *Server) Router() http.Handler {
 	mux.HandleFunc("/events_for_day", s.handleEventsForDay)
 	mux.HandleFunc("/events_for_week", s.handleEventsForWeek)
 	mux.HandleFunc("/events_for_month", s.handleEventsForMonth)
+	mux.HandleFunc("/export_ics", s.handleExportICS)
+	mux.HandleFunc("/import_ics", s.handleImportICS)
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
 	return mux
 }
 
+// handleHealthz reports whether the process is alive. It flips to 503 once
+// shutdown begins so it can double as a readiness check for callers that
+// don't distinguish the two.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if s.shuttingDown.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleReadyz reports whether the server is ready to accept new requests.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if s.shuttingDown.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
 type errorResponse struct {
 	Error string `json:"error"`
 }
@@ -56,30 +89,69 @@ func internalError(w http.ResponseWriter, msg string) {
 	writeJSON(w, http.StatusInternalServerError, errorResponse{Error: msg})
 }
 
-func parseUserID(values map[string]string) (int64, error) {
-	userStr := values["user_id"]
-	if userStr == "" {
-		return 0, errors.New("missing user_id")
-	}
-	uid, err := strconv.ParseInt(userStr, 10, 64)
-	if err != nil || uid <= 0 {
-		return 0, errors.New("invalid user_id")
-	}
-	return uid, nil
+// userIDFromRequest returns the authenticated user ID injected by the Auth
+// middleware. Handlers use this instead of trusting a client-supplied
+// user_id field, closing the spoofing hole where any caller could operate on
+// any user's events.
+func userIDFromRequest(r *http.Request) (int64, error) {
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok {
+		return 0, errors.New("missing authenticated user")
+	}
+	return userID, nil
 }
 
-func parseDate(values map[string]string) (time.Time, error) {
+func parseDate(values map[string]string, loc *time.Location) (time.Time, error) {
 	ds := values["date"]
 	if ds == "" {
 		return time.Time{}, errors.New("missing date")
 	}
-	d, err := time.Parse("2006-01-02", ds)
+	d, err := time.ParseInLocation("2006-01-02", ds, loc)
 	if err != nil {
 		return time.Time{}, errors.New("invalid date")
 	}
 	return d, nil
 }
 
+// parseTimestamp accepts RFC3339 ("2024-01-15T09:00:00-08:00"), whose
+// explicit offset is trusted as-is, or the shorter "2006-01-02T15:04"
+// wall-clock form used by plain HTML datetime inputs, which carries no
+// offset of its own and is interpreted as wall-clock time in loc.
+func parseTimestamp(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02T15:04", s, loc)
+}
+
+// parseStartEnd reads the start/end/tz triple introduced for timezone-aware
+// scheduling, falling back to the older bare "date" field so existing
+// callers keep working. Naive forms (no UTC offset of their own) are
+// interpreted as wall-clock time in the requested tz rather than UTC, so a
+// plain HTML datetime input means what its sender intended.
+func parseStartEnd(values map[string]string) (start, end time.Time, tz string, err error) {
+	tz = values["tz"]
+	loc := calendar.EventLocation(tz)
+	if v := values["start"]; v != "" {
+		start, err = parseTimestamp(v, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, "", errors.New("invalid start")
+		}
+	} else {
+		start, err = parseDate(values, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, "", err
+		}
+	}
+	if v := values["end"]; v != "" {
+		end, err = parseTimestamp(v, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, "", errors.New("invalid end")
+		}
+	}
+	return start, end, tz, nil
+}
+
 func parseBodyOrForm(r *http.Request) (map[string]string, error) {
 	ct := r.Header.Get("Content-Type")
 	if strings.HasPrefix(ct, "application/json") {
@@ -110,12 +182,12 @@ func (s *Server) handleCreateEvent(w http.ResponseWriter, r *http.Request) {
 		internalError(w, "failed to parse body")
 		return
 	}
-	uid, err := parseUserID(vals)
+	uid, err := userIDFromRequest(r)
 	if err != nil {
 		badRequest(w, err.Error())
 		return
 	}
-	date, err := parseDate(vals)
+	start, end, tz, err := parseStartEnd(vals)
 	if err != nil {
 		badRequest(w, err.Error())
 		return
@@ -125,7 +197,7 @@ func (s *Server) handleCreateEvent(w http.ResponseWriter, r *http.Request) {
 		badRequest(w, "missing event")
 		return
 	}
-	ev, err := s.Svc.CreateEvent(uid, date, text)
+	ev, err := s.Svc.CreateEvent(r.Context(), uid, start, end, tz, text, vals["recurrence"])
 	if err != nil {
 		bizError(w, err.Error())
 		return
@@ -144,12 +216,12 @@ func (s *Server) handleUpdateEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := vals["id"]
-	uid, err := parseUserID(vals)
+	uid, err := userIDFromRequest(r)
 	if err != nil {
 		badRequest(w, err.Error())
 		return
 	}
-	date, err := parseDate(vals)
+	start, end, tz, err := parseStartEnd(vals)
 	if err != nil {
 		badRequest(w, err.Error())
 		return
@@ -159,9 +231,9 @@ func (s *Server) handleUpdateEvent(w http.ResponseWriter, r *http.Request) {
 		badRequest(w, "missing event")
 		return
 	}
-	ev, err := s.Svc.UpdateEvent(id, uid, date, text)
+	ev, err := s.Svc.UpdateEvent(r.Context(), id, uid, start, end, tz, text, vals["recurrence"])
 	if err != nil {
-		if errors.Is(err, calendar.ErrNotFound) || errors.Is(err, calendar.ErrInvalidUserID) || errors.Is(err, calendar.ErrInvalidText) {
+		if errors.Is(err, calendar.ErrNotFound) || errors.Is(err, calendar.ErrInvalidUserID) || errors.Is(err, calendar.ErrInvalidText) || errors.Is(err, calendar.ErrInvalidRecurrence) {
 			bizError(w, err.Error())
 			return
 		}
@@ -182,11 +254,12 @@ func (s *Server) handleDeleteEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := vals["id"]
-	var uid int64
-	if u := vals["user_id"]; u != "" {
-		uid, _ = strconv.ParseInt(u, 10, 64)
+	uid, err := userIDFromRequest(r)
+	if err != nil {
+		badRequest(w, err.Error())
+		return
 	}
-	if err := s.Svc.DeleteEvent(id, uid); err != nil {
+	if err := s.Svc.DeleteEvent(r.Context(), id, uid); err != nil {
 		if errors.Is(err, calendar.ErrNotFound) {
 			bizError(w, err.Error())
 			return
@@ -202,19 +275,18 @@ func (s *Server) handleEventsForDay(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	uidStr := r.URL.Query().Get("user_id")
-	dateStr := r.URL.Query().Get("date")
-	uid, err := strconv.ParseInt(uidStr, 10, 64)
-	if err != nil || uid <= 0 {
-		badRequest(w, "invalid user_id")
+	uid, err := userIDFromRequest(r)
+	if err != nil {
+		badRequest(w, err.Error())
 		return
 	}
+	dateStr := r.URL.Query().Get("date")
 	day, err := time.Parse("2006-01-02", dateStr)
 	if err != nil {
 		badRequest(w, "invalid date")
 		return
 	}
-	events, err := s.Svc.EventsForDay(uid, day)
+	events, err := s.Svc.EventsForDay(r.Context(), uid, day, r.URL.Query().Get("tz"))
 	if err != nil {
 		bizError(w, err.Error())
 		return
@@ -227,23 +299,98 @@ func (s *Server) handleEventsForWeek(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	uidStr := r.URL.Query().Get("user_id")
-	dateStr := r.URL.Query().Get("date")
-	uid, err := strconv.ParseInt(uidStr, 10, 64)
-	if err != nil || uid <= 0 {
-		badRequest(w, "invalid user_id")
+	uid, err := userIDFromRequest(r)
+	if err != nil {
+		badRequest(w, err.Error())
 		return
 	}
+	dateStr := r.URL.Query().Get("date")
 	day, err := time.Parse("2006-01-02", dateStr)
 	if err != nil {
 		badRequest(w, "invalid date")
 		return
 	}
-	events, err := s.Svc.EventsForWeek(uid, day)
+	events, err := s.Svc.EventsForWeek(r.Context(), uid, day, r.URL.Query().Get("tz"))
+	if err != nil {
+		bizError(w, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, resultResponse[[]calendar.Event]{Result: events})
+}
+
+// icsExportWindow defines how far export_ics looks when a range isn't given:
+// far enough back and forward to catch any realistic recurring series.
+const icsExportWindow = 10 * 365 * 24 * time.Hour
+
+func (s *Server) handleExportICS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	uid, err := userIDFromRequest(r)
+	if err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	now := time.Now()
+	from, to := now.Add(-icsExportWindow), now.Add(icsExportWindow)
+	if v := r.URL.Query().Get("from"); v != "" {
+		from, err = time.Parse("2006-01-02", v)
+		if err != nil {
+			badRequest(w, "invalid from")
+			return
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		to, err = time.Parse("2006-01-02", v)
+		if err != nil {
+			badRequest(w, "invalid to")
+			return
+		}
+	}
+	data, err := s.Svc.ExportICS(r.Context(), uid, from, to)
 	if err != nil {
 		bizError(w, err.Error())
 		return
 	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="calendar.ics"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+func (s *Server) handleImportICS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	uid, err := userIDFromRequest(r)
+	if err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+
+	var body io.Reader = r.Body
+	if ct := r.Header.Get("Content-Type"); strings.HasPrefix(ct, "multipart/") {
+		file, _, ferr := r.FormFile("file")
+		if ferr != nil {
+			badRequest(w, "missing file")
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+	defer r.Body.Close()
+
+	events, err := s.Svc.ImportICS(r.Context(), uid, body)
+	if err != nil {
+		if errors.Is(err, calendar.ErrInvalidICS) || errors.Is(err, calendar.ErrInvalidUserID) {
+			badRequest(w, err.Error())
+			return
+		}
+		internalError(w, err.Error())
+		return
+	}
 	writeJSON(w, http.StatusOK, resultResponse[[]calendar.Event]{Result: events})
 }
 
@@ -252,19 +399,18 @@ func (s *Server) handleEventsForMonth(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	uidStr := r.URL.Query().Get("user_id")
-	dateStr := r.URL.Query().Get("date")
-	uid, err := strconv.ParseInt(uidStr, 10, 64)
-	if err != nil || uid <= 0 {
-		badRequest(w, "invalid user_id")
+	uid, err := userIDFromRequest(r)
+	if err != nil {
+		badRequest(w, err.Error())
 		return
 	}
+	dateStr := r.URL.Query().Get("date")
 	day, err := time.Parse("2006-01-02", dateStr)
 	if err != nil {
 		badRequest(w, "invalid date")
 		return
 	}
-	events, err := s.Svc.EventsForMonth(uid, day)
+	events, err := s.Svc.EventsForMonth(r.Context(), uid, day, r.URL.Query().Get("tz"))
 	if err != nil {
 		bizError(w, err.Error())
 		return
@@ -0,0 +1,50 @@
+package httpserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseStartEndInterpretsNaiveFormsInRequestedTZ(t *testing.T) {
+	start, _, _, err := parseStartEnd(map[string]string{
+		"start": "2024-01-15T09:00",
+		"tz":    "America/Los_Angeles",
+	})
+	if err != nil {
+		t.Fatalf("parseStartEnd: %v", err)
+	}
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2024, 1, 15, 9, 0, 0, 0, la)
+	if !start.Equal(want) {
+		t.Fatalf("got %v, want %v (9am in America/Los_Angeles, not UTC)", start, want)
+	}
+}
+
+func TestParseStartEndBareDateUsesRequestedTZ(t *testing.T) {
+	start, _, _, err := parseStartEnd(map[string]string{
+		"date": "2024-01-15",
+		"tz":   "America/Los_Angeles",
+	})
+	if err != nil {
+		t.Fatalf("parseStartEnd: %v", err)
+	}
+	if start.Day() != 15 {
+		t.Fatalf("got day %d, want 15 (bare date should bucket onto the requested day in the requested tz)", start.Day())
+	}
+}
+
+func TestParseStartEndRFC3339PreservesItsOwnOffset(t *testing.T) {
+	start, _, _, err := parseStartEnd(map[string]string{
+		"start": "2024-01-15T09:00:00-08:00",
+		"tz":    "Asia/Tokyo",
+	})
+	if err != nil {
+		t.Fatalf("parseStartEnd: %v", err)
+	}
+	if _, offset := start.Zone(); offset != -8*3600 {
+		t.Fatalf("want RFC3339's own -08:00 offset preserved regardless of tz param, got offset %d", offset)
+	}
+}